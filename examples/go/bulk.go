@@ -0,0 +1,266 @@
+package triggersapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultMaxBatchSize = 100
+
+// BulkOptions configures Client.CreateEventsBulk and Client.CreateEventsFromChan.
+type BulkOptions struct {
+	// MaxBatchSize is the number of events sent per request. Defaults to 100.
+	MaxBatchSize int
+	// MaxConcurrency is the number of chunks dispatched in parallel. Defaults to 1.
+	MaxConcurrency int
+	// FlushInterval bounds how long CreateEventsFromChan buffers partial
+	// batches before flushing them, even if MaxBatchSize hasn't been reached.
+	FlushInterval time.Duration
+}
+
+func (o BulkOptions) maxBatchSize() int {
+	if o.MaxBatchSize > 0 {
+		return o.MaxBatchSize
+	}
+	return defaultMaxBatchSize
+}
+
+func (o BulkOptions) maxConcurrency() int {
+	if o.MaxConcurrency > 0 {
+		return o.MaxConcurrency
+	}
+	return 1
+}
+
+// BulkEventResult is the outcome of a single input to CreateEventsBulk.
+type BulkEventResult struct {
+	Index   int
+	EventID string
+	Error   *APIError
+}
+
+// BulkResult aggregates the per-input outcomes of a bulk create call.
+type BulkResult struct {
+	Results   []BulkEventResult
+	Succeeded int
+	Failed    int
+}
+
+// APIError is a structured error returned by the Triggers API.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, e.Message)
+}
+
+type bulkEventsRequest struct {
+	Events []bulkEventItem `json:"events"`
+}
+
+// bulkEventItem is the wire representation of a single CreateEventOptions
+// within a bulk request. CreateEventOptions has no json tags of its own
+// (its fields are only ever hand-assembled into a request body, the way
+// CreateEvent does), so bulk requests build this snake_case shape
+// explicitly instead of marshaling CreateEventOptions directly.
+type bulkEventItem struct {
+	Source    string                 `json:"source"`
+	EventType string                 `json:"event_type"`
+	Payload   map[string]interface{} `json:"payload"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+type bulkEventsResponseItem struct {
+	Index   int       `json:"index"`
+	EventID string    `json:"event_id,omitempty"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+type bulkEventsResponse struct {
+	Results   []bulkEventsResponseItem `json:"results"`
+	RequestID string                   `json:"request_id"`
+}
+
+// CreateEventsBulk creates many events in as few round trips as possible,
+// splitting opts into chunks of BulkOptions.MaxBatchSize and dispatching up
+// to BulkOptions.MaxConcurrency chunks concurrently. Each input's outcome is
+// reported independently so callers can retry only the failures.
+func (c *Client) CreateEventsBulk(ctx context.Context, opts []CreateEventOptions, options BulkOptions) (*BulkResult, error) {
+	chunkSize := options.maxBatchSize()
+	chunks := chunkCreateEventOptions(opts, chunkSize)
+
+	results := make([]BulkEventResult, len(opts))
+	sem := make(chan struct{}, options.maxConcurrency())
+	var wg sync.WaitGroup
+	var firstErr error
+	var mu sync.Mutex
+
+	for i, chunk := range chunks {
+		offset := i * chunkSize
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(offset int, chunk []CreateEventOptions) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			items, err := c.createEventsChunk(ctx, chunk)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				for j := range chunk {
+					results[offset+j] = BulkEventResult{Index: offset + j, Error: &APIError{Message: err.Error()}}
+				}
+				return
+			}
+			for j, item := range items {
+				results[offset+j] = BulkEventResult{Index: offset + j, EventID: item.EventID, Error: item.Error}
+			}
+		}(offset, chunk)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil && allFailed(results) {
+		return nil, firstErr
+	}
+
+	result := &BulkResult{Results: results}
+	for _, r := range results {
+		if r.Error != nil {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+	}
+	return result, nil
+}
+
+func allFailed(results []BulkEventResult) bool {
+	for _, r := range results {
+		if r.Error == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func chunkCreateEventOptions(opts []CreateEventOptions, size int) [][]CreateEventOptions {
+	var chunks [][]CreateEventOptions
+	for size < len(opts) {
+		opts, chunks = opts[size:], append(chunks, opts[0:size:size])
+	}
+	return append(chunks, opts)
+}
+
+func (c *Client) createEventsChunk(ctx context.Context, chunk []CreateEventOptions) ([]bulkEventsResponseItem, error) {
+	requestID := ""
+	if len(chunk) > 0 {
+		requestID = chunk[0].RequestID
+	}
+
+	items := make([]bulkEventItem, len(chunk))
+	for i, opt := range chunk {
+		payload, encrypted, err := c.encryptPayload(opt.Payload, c.keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata := opt.Metadata
+		if encrypted {
+			metadata = cloneMetadata(metadata)
+			metadata["encryption"] = encryptionAlgorithm
+		}
+
+		items[i] = bulkEventItem{
+			Source:    opt.Source,
+			EventType: opt.EventType,
+			Payload:   payload,
+			Metadata:  metadata,
+			RequestID: opt.RequestID,
+		}
+	}
+
+	respBody, err := c.makeRequest(ctx, "POST", "/v1/events/bulk", bulkEventsRequest{Events: items}, nil, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed bulkEventsResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.Results, nil
+}
+
+// CreateEventsFromChan consumes CreateEventOptions from in, batching them
+// into chunks of BulkOptions.MaxBatchSize and flushing whenever a batch
+// fills up or FlushInterval elapses, whichever comes first. It is intended
+// for high-throughput producers that don't have all events in hand upfront.
+// The returned channel carries one BulkResult per flushed chunk and is
+// closed once in is drained or ctx is canceled.
+func (c *Client) CreateEventsFromChan(ctx context.Context, in <-chan CreateEventOptions, options BulkOptions) <-chan *BulkResult {
+	out := make(chan *BulkResult)
+	chunkSize := options.maxBatchSize()
+	flushInterval := options.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	go func() {
+		defer close(out)
+
+		buf := make([]CreateEventOptions, 0, chunkSize)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		flush := func() {
+			if len(buf) == 0 {
+				return
+			}
+			result, err := c.CreateEventsBulk(ctx, buf, options)
+			if err != nil {
+				result = &BulkResult{Failed: len(buf)}
+				for i := range buf {
+					result.Results = append(result.Results, BulkEventResult{Index: i, Error: &APIError{Message: err.Error()}})
+				}
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+			}
+			buf = make([]CreateEventOptions, 0, chunkSize)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				flush()
+				return
+			case opt, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				buf = append(buf, opt)
+				if len(buf) >= chunkSize {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	return out
+}
@@ -2,6 +2,7 @@ package triggersapi
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -21,6 +23,22 @@ type Client struct {
 	timeout      time.Duration
 	signingSecret string
 	httpClient   *http.Client
+	// streamClient is used for Subscribe's long-lived SSE requests. It has no
+	// Timeout: http.Client.Timeout bounds the entire request including
+	// reading the body, which would force-close and reconnect a subscription
+	// every ClientOptions.Timeout. The per-read idle deadline set on the
+	// connection (see sseIdleTimeout) and ctx take over that job instead.
+	streamClient *http.Client
+
+	autoIdempotency      bool
+	idempotencyWindow    time.Duration
+	idempotencyMu        sync.Mutex
+	idempotencyCache     map[string]idempotencyEntry
+	idempotencyLastPurge time.Time
+
+	encryptedFields []string
+	keyID           string
+	keyProvider     KeyProvider
 }
 
 // ClientOptions configures the client
@@ -29,6 +47,28 @@ type ClientOptions struct {
 	BaseURL       string
 	Timeout       time.Duration
 	SigningSecret string
+
+	// AutoIdempotency generates a random idempotency key for every POST that
+	// doesn't set one explicitly via WithIdempotencyKey.
+	AutoIdempotency bool
+	// IdempotencyWindow bounds how long a cached idempotency key's signature
+	// is reused by retries. Defaults to 24 hours.
+	IdempotencyWindow time.Duration
+
+	// EncryptionKey AES-256-GCM encrypts the fields named in EncryptedFields
+	// before they reach the Triggers service, and decrypts them transparently
+	// on the way back out. Must be 32 bytes. Ignored if KeyProvider is set.
+	EncryptionKey []byte
+	// KeyID identifies EncryptionKey (or the default key handed to
+	// KeyProvider) inside the encryption envelope, so a later key rotation
+	// can tell which key encrypted a given field.
+	KeyID string
+	// EncryptedFields lists dot-separated JSON paths within CreateEvent's
+	// Payload to encrypt, e.g. "customer.ssn".
+	EncryptedFields []string
+	// KeyProvider resolves encryption keys by ID for multi-tenant callers
+	// that rotate keys. Takes precedence over EncryptionKey.
+	KeyProvider KeyProvider
 }
 
 // NewClient creates a new Triggers API client
@@ -45,6 +85,14 @@ func NewClient(options ClientOptions) (*Client, error) {
 		options.Timeout = 30 * time.Second
 	}
 
+	keyProvider := options.KeyProvider
+	if keyProvider == nil && options.EncryptionKey != nil {
+		if len(options.EncryptionKey) != 32 {
+			return nil, fmt.Errorf("EncryptionKey must be 32 bytes, got %d", len(options.EncryptionKey))
+		}
+		keyProvider = staticKeyProvider{keyID: options.KeyID, key: options.EncryptionKey}
+	}
+
 	return &Client{
 		apiKey:        options.APIKey,
 		baseURL:       options.BaseURL,
@@ -53,6 +101,12 @@ func NewClient(options ClientOptions) (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: options.Timeout,
 		},
+		streamClient:      &http.Client{},
+		autoIdempotency:   options.AutoIdempotency,
+		idempotencyWindow: options.IdempotencyWindow,
+		encryptedFields:   options.EncryptedFields,
+		keyID:             options.KeyID,
+		keyProvider:       keyProvider,
 	}, nil
 }
 
@@ -114,7 +168,40 @@ func (c *Client) generateSignature(method, path, queryString, timestamp string,
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
 
-func (c *Client) makeRequest(method, endpoint string, body interface{}, params map[string]string, requestID string) ([]byte, error) {
+// signRequest adds the HMAC signature headers to req, using bodyHash as the
+// hash of whatever body (if any) was sent with the request. It is a no-op
+// when the client has no signing secret configured. Shared by makeRequest
+// and the long-lived connections opened by Subscribe.
+func (c *Client) signRequest(req *http.Request, bodyHash string) {
+	if c.signingSecret == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := c.generateSignature(req.Method, req.URL.Path, req.URL.RawQuery, timestamp, bodyHash)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature", signature)
+	req.Header.Set("X-Signature-Version", "v1")
+}
+
+// makeRequest issues a single logical API call, retrying according to the
+// resolved RetryPolicy. A POST is only retried when an idempotency key is
+// present (explicitly via WithIdempotencyKey or generated by
+// ClientOptions.AutoIdempotency); GET/DELETE are always eligible.
+//
+// ctx bounds the whole call, retries included. If ctx has no deadline,
+// ClientOptions.Timeout is applied as a fallback deadline so existing
+// callers keep their current timeout behavior without setting one
+// themselves.
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, body interface{}, params map[string]string, requestID string, opts ...RequestOption) ([]byte, error) {
+	cfg := resolveRequestConfig(opts)
+	ctx = resolveContext(ctx, cfg)
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
 	// Build URL
 	reqURL, err := url.Parse(c.baseURL + endpoint)
 	if err != nil {
@@ -141,10 +228,45 @@ func (c *Client) makeRequest(method, endpoint string, body interface{}, params m
 		}
 	}
 
-	// Create request
-	req, err := http.NewRequest(method, reqURL.String(), bytes.NewBuffer(bodyBytes))
+	idempotencyKey := cfg.idempotencyKey
+	if idempotencyKey == "" && c.autoIdempotency && method == http.MethodPost {
+		if idempotencyKey, err = newIdempotencyKey(); err != nil {
+			return nil, err
+		}
+	}
+	canRetry := method != http.MethodPost || idempotencyKey != ""
+
+	var lastErr error
+	var retryAfter time.Duration
+	for attempt := 0; attempt < cfg.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(cfg.retry.backoff(attempt-1, retryAfter)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		respBody, statusCode, after, reqErr := c.doRequest(ctx, cfg, method, reqURL, bodyBytes, requestID, idempotencyKey)
+		if reqErr == nil {
+			return respBody, nil
+		}
+		lastErr, retryAfter = reqErr, after
+
+		if !canRetry || (statusCode != 0 && !cfg.retry.isRetryableStatus(statusCode)) {
+			return nil, reqErr
+		}
+	}
+	return nil, lastErr
+}
+
+// doRequest performs one HTTP attempt and reports the status code (0 on
+// network failure) and any Retry-After delay so makeRequest can decide
+// whether and how long to wait before retrying.
+func (c *Client) doRequest(ctx context.Context, cfg *requestConfig, method string, reqURL *url.URL, bodyBytes []byte, requestID, idempotencyKey string) ([]byte, int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), bytes.NewReader(bodyBytes))
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
 	// Set headers
@@ -153,31 +275,38 @@ func (c *Client) makeRequest(method, endpoint string, body interface{}, params m
 	if requestID != "" {
 		req.Header.Set("X-Request-ID", requestID)
 	}
+	for k, v := range cfg.headers {
+		req.Header.Set(k, v)
+	}
 
-	// Add signature if signing secret is provided
-	if c.signingSecret != "" {
-		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-		bodyHash := sha256Hash(bodyBytes)
-		signature := c.generateSignature(method, reqURL.Path, reqURL.RawQuery, timestamp, bodyHash)
-		req.Header.Set("X-Signature-Timestamp", timestamp)
-		req.Header.Set("X-Signature", signature)
-		req.Header.Set("X-Signature-Version", "v1")
+	bodyHash := sha256Hash(bodyBytes)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		timestamp, signature := c.idempotencySignature(idempotencyKey, method, reqURL.Path, reqURL.RawQuery, bodyHash)
+		if c.signingSecret != "" {
+			req.Header.Set("X-Signature-Timestamp", timestamp)
+			req.Header.Set("X-Signature", signature)
+			req.Header.Set("X-Signature-Version", "v1")
+		}
+	} else {
+		c.signRequest(req, bodyHash)
 	}
 
 	// Make request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, 0, err
 	}
 
 	// Check for errors
 	if resp.StatusCode >= 400 {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
 		var errorResp struct {
 			Error struct {
 				Code    string                 `json:"code"`
@@ -185,13 +314,28 @@ func (c *Client) makeRequest(method, endpoint string, body interface{}, params m
 				Details map[string]interface{} `json:"details"`
 			} `json:"error"`
 		}
-		if err := json.Unmarshal(respBody, &errorResp); err == nil {
-			return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
+		if err := json.Unmarshal(respBody, &errorResp); err == nil && errorResp.Error.Message != "" {
+			return nil, resp.StatusCode, retryAfter, fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
+		return nil, resp.StatusCode, retryAfter, fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
 	}
 
-	return respBody, nil
+	return respBody, resp.StatusCode, 0, nil
+}
+
+// parseRetryAfter interprets a Retry-After header as either delay-seconds
+// or an HTTP-date, returning 0 if it's absent or unparseable.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 func sha256Hash(data []byte) string {
@@ -199,18 +343,39 @@ func sha256Hash(data []byte) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// CreateEvent creates a new event
-func (c *Client) CreateEvent(options CreateEventOptions) (*EventResponse, error) {
+// CreateEvent creates a new event. ctx bounds the call, including retries;
+// pass WithIdempotencyKey to make retries (automatic or manual) safe to
+// replay against the server. If the client was configured with an
+// EncryptionKey or KeyProvider, the fields named in EncryptedFields are
+// encrypted before the request is sent.
+func (c *Client) CreateEvent(ctx context.Context, options CreateEventOptions, opts ...RequestOption) (*EventResponse, error) {
+	cfg := resolveRequestConfig(opts)
+	keyID := c.keyID
+	if cfg.keyID != "" {
+		keyID = cfg.keyID
+	}
+
+	payload, encrypted, err := c.encryptPayload(options.Payload, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := options.Metadata
+	if encrypted {
+		metadata = cloneMetadata(metadata)
+		metadata["encryption"] = encryptionAlgorithm
+	}
+
 	data := map[string]interface{}{
 		"source":      options.Source,
 		"event_type":  options.EventType,
-		"payload":     options.Payload,
+		"payload":     payload,
 	}
-	if options.Metadata != nil {
-		data["metadata"] = options.Metadata
+	if metadata != nil {
+		data["metadata"] = metadata
 	}
 
-	respBody, err := c.makeRequest("POST", "/v1/events", data, nil, options.RequestID)
+	respBody, err := c.makeRequest(ctx, "POST", "/v1/events", data, nil, options.RequestID, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -223,9 +388,19 @@ func (c *Client) CreateEvent(options CreateEventOptions) (*EventResponse, error)
 	return &eventResp, nil
 }
 
-// GetEvent gets detailed information about a specific event
-func (c *Client) GetEvent(eventID, requestID string) (*EventDetailResponse, error) {
-	respBody, err := c.makeRequest("GET", fmt.Sprintf("/v1/events/%s", eventID), nil, nil, requestID)
+// CreateEventNoContext is the pre-context signature of CreateEvent, retained
+// during the migration to context-aware calls.
+//
+// Deprecated: use CreateEvent, which takes a context.Context, instead.
+func (c *Client) CreateEventNoContext(options CreateEventOptions, opts ...RequestOption) (*EventResponse, error) {
+	return c.CreateEvent(context.Background(), options, opts...)
+}
+
+// GetEvent gets detailed information about a specific event. If the client
+// was configured with an EncryptionKey or KeyProvider, any encrypted fields
+// in the payload are transparently decrypted.
+func (c *Client) GetEvent(ctx context.Context, eventID, requestID string, opts ...RequestOption) (*EventDetailResponse, error) {
+	respBody, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/v1/events/%s", eventID), nil, nil, requestID, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -235,11 +410,24 @@ func (c *Client) GetEvent(eventID, requestID string) (*EventDetailResponse, erro
 		return nil, err
 	}
 
+	eventResp.Payload, err = c.decryptPayload(eventResp.Payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return &eventResp, nil
 }
 
+// GetEventNoContext is the pre-context signature of GetEvent, retained
+// during the migration to context-aware calls.
+//
+// Deprecated: use GetEvent, which takes a context.Context, instead.
+func (c *Client) GetEventNoContext(eventID, requestID string, opts ...RequestOption) (*EventDetailResponse, error) {
+	return c.GetEvent(context.Background(), eventID, requestID, opts...)
+}
+
 // GetInbox gets pending events with pagination and filtering
-func (c *Client) GetInbox(options GetInboxOptions) (*InboxResponse, error) {
+func (c *Client) GetInbox(ctx context.Context, options GetInboxOptions, opts ...RequestOption) (*InboxResponse, error) {
 	params := make(map[string]string)
 	params["limit"] = strconv.Itoa(options.Limit)
 	if options.Cursor != "" {
@@ -252,7 +440,7 @@ func (c *Client) GetInbox(options GetInboxOptions) (*InboxResponse, error) {
 		params["event_type"] = options.EventType
 	}
 
-	respBody, err := c.makeRequest("GET", "/v1/inbox", nil, params, options.RequestID)
+	respBody, err := c.makeRequest(ctx, "GET", "/v1/inbox", nil, params, options.RequestID, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -262,18 +450,54 @@ func (c *Client) GetInbox(options GetInboxOptions) (*InboxResponse, error) {
 		return nil, err
 	}
 
+	for _, ev := range inboxResp.Events {
+		payload, ok := ev["payload"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		decrypted, err := c.decryptPayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		ev["payload"] = decrypted
+	}
+
 	return &inboxResp, nil
 }
 
+// GetInboxNoContext is the pre-context signature of GetInbox, retained
+// during the migration to context-aware calls.
+//
+// Deprecated: use GetInbox, which takes a context.Context, instead.
+func (c *Client) GetInboxNoContext(options GetInboxOptions, opts ...RequestOption) (*InboxResponse, error) {
+	return c.GetInbox(context.Background(), options, opts...)
+}
+
 // AcknowledgeEvent acknowledges an event
-func (c *Client) AcknowledgeEvent(eventID, requestID string) error {
-	_, err := c.makeRequest("POST", fmt.Sprintf("/v1/events/%s/ack", eventID), nil, nil, requestID)
+func (c *Client) AcknowledgeEvent(ctx context.Context, eventID, requestID string, opts ...RequestOption) error {
+	_, err := c.makeRequest(ctx, "POST", fmt.Sprintf("/v1/events/%s/ack", eventID), nil, nil, requestID, opts...)
 	return err
 }
 
+// AcknowledgeEventNoContext is the pre-context signature of
+// AcknowledgeEvent, retained during the migration to context-aware calls.
+//
+// Deprecated: use AcknowledgeEvent, which takes a context.Context, instead.
+func (c *Client) AcknowledgeEventNoContext(eventID, requestID string, opts ...RequestOption) error {
+	return c.AcknowledgeEvent(context.Background(), eventID, requestID, opts...)
+}
+
 // DeleteEvent deletes an event
-func (c *Client) DeleteEvent(eventID, requestID string) error {
-	_, err := c.makeRequest("DELETE", fmt.Sprintf("/v1/events/%s", eventID), nil, nil, requestID)
+func (c *Client) DeleteEvent(ctx context.Context, eventID, requestID string, opts ...RequestOption) error {
+	_, err := c.makeRequest(ctx, "DELETE", fmt.Sprintf("/v1/events/%s", eventID), nil, nil, requestID, opts...)
 	return err
 }
 
+// DeleteEventNoContext is the pre-context signature of DeleteEvent, retained
+// during the migration to context-aware calls.
+//
+// Deprecated: use DeleteEvent, which takes a context.Context, instead.
+func (c *Client) DeleteEventNoContext(eventID, requestID string, opts ...RequestOption) error {
+	return c.DeleteEvent(context.Background(), eventID, requestID, opts...)
+}
+
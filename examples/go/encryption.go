@@ -0,0 +1,318 @@
+package triggersapi
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	encryptionEnvelopeVersion = "v1"
+	encryptionAlgorithm       = "aes-256-gcm/v1"
+)
+
+// KeyProvider resolves encryption keys by ID, letting multi-tenant callers
+// rotate keys without reconfiguring ClientOptions.EncryptionKey. keyID is
+// empty when decrypting an envelope that predates key rotation.
+type KeyProvider interface {
+	KeyFor(keyID string) ([]byte, error)
+}
+
+// staticKeyProvider wraps the single key set via ClientOptions.EncryptionKey
+// for clients that don't need multi-key rotation.
+type staticKeyProvider struct {
+	keyID string
+	key   []byte
+}
+
+func (p staticKeyProvider) KeyFor(keyID string) ([]byte, error) {
+	if keyID != "" && keyID != p.keyID {
+		return nil, fmt.Errorf("triggersapi: unknown encryption key id %q", keyID)
+	}
+	return p.key, nil
+}
+
+// encryptionEnvelope is the JSON shape an encrypted payload field is
+// replaced with. Encrypted/decrypted fields round-trip through it.
+type encryptionEnvelope struct {
+	Enc        string `json:"__enc"`
+	KeyID      string `json:"key_id,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// WithEncryptionKeyID overrides the key ID used to encrypt a single
+// CreateEvent call, looked up via the client's KeyProvider. Used by
+// RotateEventKey to re-encrypt under a new key.
+func WithEncryptionKeyID(keyID string) RequestOption {
+	return func(cfg *requestConfig) { cfg.keyID = keyID }
+}
+
+// encryptPayload returns a deep copy of payload with every field named in
+// c.encryptedFields replaced by an AES-256-GCM envelope, plus whether any
+// field was actually found and encrypted. payload is returned unchanged,
+// with encrypted false, if encryption isn't configured.
+func (c *Client) encryptPayload(payload map[string]interface{}, keyID string) (map[string]interface{}, bool, error) {
+	if len(c.encryptedFields) == 0 || c.keyProvider == nil || payload == nil {
+		return payload, false, nil
+	}
+
+	out, err := deepCopyJSONMap(payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key, err := c.keyProvider.KeyFor(keyID)
+	if err != nil {
+		return nil, false, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	encrypted := false
+	for _, field := range c.encryptedFields {
+		value, ok := getJSONPath(out, field)
+		if !ok {
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, false, err
+		}
+
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, false, err
+		}
+		ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+		envelope, err := envelopeToMap(encryptionEnvelope{
+			Enc:        encryptionEnvelopeVersion,
+			KeyID:      keyID,
+			Nonce:      base64.StdEncoding.EncodeToString(nonce),
+			Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		setJSONPath(out, field, envelope)
+		encrypted = true
+	}
+	return out, encrypted, nil
+}
+
+// decryptPayload returns a deep copy of payload with every encryption
+// envelope found anywhere in it replaced by its decrypted value, or payload
+// unchanged if no KeyProvider is configured.
+func (c *Client) decryptPayload(payload map[string]interface{}) (map[string]interface{}, error) {
+	if c.keyProvider == nil || payload == nil {
+		return payload, nil
+	}
+	out, err := deepCopyJSONMap(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.decryptInPlace(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) decryptInPlace(m map[string]interface{}) error {
+	for k, v := range m {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if isEnvelope(val) {
+				plain, err := c.decryptEnvelope(val)
+				if err != nil {
+					return err
+				}
+				m[k] = plain
+				continue
+			}
+			if err := c.decryptInPlace(val); err != nil {
+				return err
+			}
+		case []interface{}:
+			if err := c.decryptInSlice(val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) decryptInSlice(items []interface{}) error {
+	for i, item := range items {
+		child, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if isEnvelope(child) {
+			plain, err := c.decryptEnvelope(child)
+			if err != nil {
+				return err
+			}
+			items[i] = plain
+			continue
+		}
+		if err := c.decryptInPlace(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isEnvelope(m map[string]interface{}) bool {
+	enc, ok := m["__enc"].(string)
+	return ok && enc == encryptionEnvelopeVersion
+}
+
+func (c *Client) decryptEnvelope(env map[string]interface{}) (interface{}, error) {
+	keyID, _ := env["key_id"].(string)
+	nonceB64, _ := env["nonce"].(string)
+	ciphertextB64, _ := env["ciphertext"].(string)
+
+	key, err := c.keyProvider.KeyFor(keyID)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(nonceB64)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(plaintext, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// RotateEventKey re-encrypts eventID's payload under newKeyID by fetching
+// the event (which transparently decrypts it under its current key),
+// re-posting it encrypted under newKeyID, and only then deleting the
+// original. The Triggers API has no in-place update, so the returned event
+// has a new EventID. The re-post happens before the delete so a failure to
+// create the new copy leaves the original event intact instead of losing it.
+func (c *Client) RotateEventKey(ctx context.Context, eventID, newKeyID string, opts ...RequestOption) (*EventResponse, error) {
+	detail, err := c.GetEvent(ctx, eventID, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := c.CreateEvent(ctx, CreateEventOptions{
+		Source:    detail.Source,
+		EventType: detail.EventType,
+		Payload:   detail.Payload,
+		Metadata:  detail.Metadata,
+	}, append(opts, WithEncryptionKeyID(newKeyID))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.DeleteEvent(ctx, eventID, "", opts...); err != nil {
+		return nil, fmt.Errorf("re-encrypted event %s created but failed to delete original %s: %w", created.EventID, eventID, err)
+	}
+
+	return created, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func envelopeToMap(env encryptionEnvelope) (map[string]interface{}, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// cloneMetadata returns a shallow copy of m (or a fresh map if m is nil) so
+// CreateEvent can stamp metadata["encryption"] without mutating the caller's
+// CreateEventOptions.Metadata.
+func cloneMetadata(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func deepCopyJSONMap(m map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// getJSONPath looks up a dot-separated path of object keys within m.
+func getJSONPath(m map[string]interface{}, path string) (interface{}, bool) {
+	segments := strings.Split(path, ".")
+	var cur interface{} = m
+	for _, seg := range segments {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = obj[seg]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setJSONPath overwrites the value at a dot-separated path of object keys
+// within m. It's a no-op if an intermediate segment isn't an object.
+func setJSONPath(m map[string]interface{}, path string, value interface{}) {
+	segments := strings.Split(path, ".")
+	cur := m
+	for i, seg := range segments {
+		if i == len(segments)-1 {
+			cur[seg] = value
+			return
+		}
+		next, ok := cur[seg].(map[string]interface{})
+		if !ok {
+			return
+		}
+		cur = next
+	}
+}
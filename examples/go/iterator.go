@@ -0,0 +1,208 @@
+package triggersapi
+
+import (
+	"context"
+	"sync"
+)
+
+// InboxIterator pages through GetInbox transparently, buffering one page at
+// a time and following next_cursor until it's empty. Obtain one with
+// Client.InboxIterator.
+type InboxIterator struct {
+	client  *Client
+	ctx     context.Context
+	options GetInboxOptions
+	opts    []RequestOption
+
+	// prefetch is the number of pages to fetch ahead of the caller. It has
+	// no public setter; ForEachEvent is the only caller that sets it.
+	prefetch int
+	pages    chan inboxPage
+	started  bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	events    []map[string]interface{}
+	pos       int
+	cursor    string
+	exhausted bool
+	err       error
+}
+
+type inboxPage struct {
+	events     []map[string]interface{}
+	nextCursor string
+	err        error
+}
+
+// InboxIterator returns an iterator over the events GetInbox would return
+// for options, transparently following next_cursor across calls.
+func (c *Client) InboxIterator(ctx context.Context, options GetInboxOptions, opts ...RequestOption) *InboxIterator {
+	return &InboxIterator{
+		client:  c,
+		ctx:     ctx,
+		options: options,
+		opts:    opts,
+		cursor:  options.Cursor,
+		closed:  make(chan struct{}),
+	}
+}
+
+// Next advances to the next event, fetching another page when the current
+// one is exhausted. It returns false once iteration is complete or an error
+// occurred; call Err afterward to tell the two apart.
+func (it *InboxIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for it.pos >= len(it.events) {
+		if it.exhausted {
+			return false
+		}
+		if err := it.fetchNextPage(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	it.pos++
+	return true
+}
+
+// Event returns the event the most recent call to Next advanced to.
+func (it *InboxIterator) Event() map[string]interface{} {
+	if it.pos == 0 || it.pos > len(it.events) {
+		return nil
+	}
+	return it.events[it.pos-1]
+}
+
+// Err returns the error, if any, that stopped iteration.
+func (it *InboxIterator) Err() error {
+	return it.err
+}
+
+// Close signals the background prefetch goroutine, if one was started, to
+// stop once its current GetInbox call returns rather than fetching or
+// delivering another page. Callers that stop calling Next before it returns
+// false (for example ForEachEvent returning early from a callback error)
+// must call Close to avoid leaking the goroutine; it is a no-op otherwise
+// and safe to call more than once. Use a ctx with a deadline if the
+// in-flight request itself also needs to be cut short.
+func (it *InboxIterator) Close() {
+	it.closeOnce.Do(func() { close(it.closed) })
+}
+
+func (it *InboxIterator) fetchNextPage() error {
+	it.ensureStarted()
+
+	if it.prefetch <= 0 {
+		options := it.options
+		options.Cursor = it.cursor
+		resp, err := it.client.GetInbox(it.ctx, options, it.opts...)
+		if err != nil {
+			return err
+		}
+		it.events, it.pos, it.cursor = resp.Events, 0, resp.Pagination.NextCursor
+		if it.cursor == "" {
+			it.exhausted = true
+		}
+		return nil
+	}
+
+	page, ok := <-it.pages
+	if !ok {
+		it.exhausted = true
+		return nil
+	}
+	if page.err != nil {
+		return page.err
+	}
+	it.events, it.pos = page.events, 0
+	return nil
+}
+
+// ensureStarted lazily kicks off the prefetch goroutine on the first call
+// to Next, once it.prefetch has been set by ForEachEvent.
+func (it *InboxIterator) ensureStarted() {
+	if it.started {
+		return
+	}
+	it.started = true
+	if it.prefetch <= 0 {
+		return
+	}
+	it.pages = make(chan inboxPage, it.prefetch)
+	go it.prefetchLoop()
+}
+
+// prefetchLoop fetches pages ahead of the caller so the next HTTP round
+// trip overlaps with processing of the current page. It closes it.pages
+// after delivering the last page (the one whose next_cursor is empty), after
+// a fetch error, or as soon as it.closed is closed — which is what lets
+// Close unblock a send that the caller has stopped draining.
+func (it *InboxIterator) prefetchLoop() {
+	defer close(it.pages)
+
+	cursor := it.cursor
+	for {
+		options := it.options
+		options.Cursor = cursor
+		resp, err := it.client.GetInbox(it.ctx, options, it.opts...)
+		if err != nil {
+			select {
+			case it.pages <- inboxPage{err: err}:
+			case <-it.ctx.Done():
+			case <-it.closed:
+			}
+			return
+		}
+
+		cursor = resp.Pagination.NextCursor
+		select {
+		case it.pages <- inboxPage{events: resp.Events, nextCursor: cursor}:
+		case <-it.ctx.Done():
+			return
+		case <-it.closed:
+			return
+		}
+		if cursor == "" {
+			return
+		}
+	}
+}
+
+// ForEachOptions configures ForEachEvent.
+type ForEachOptions struct {
+	GetInboxOptions
+
+	// AutoAck acknowledges each event after fn returns nil for it.
+	AutoAck bool
+	// PrefetchPages overlaps fetching up to this many pages ahead of the
+	// caller's processing of the current page. Zero disables prefetch.
+	PrefetchPages int
+}
+
+// ForEachEvent pages through the inbox, invoking fn for each event. It stops
+// at the first error returned by fn or encountered while paging. When
+// options.AutoAck is set, each event is acknowledged after fn returns nil.
+func (c *Client) ForEachEvent(ctx context.Context, options ForEachOptions, fn func(map[string]interface{}) error, opts ...RequestOption) error {
+	it := c.InboxIterator(ctx, options.GetInboxOptions, opts...)
+	it.prefetch = options.PrefetchPages
+	defer it.Close()
+
+	for it.Next() {
+		ev := it.Event()
+		if err := fn(ev); err != nil {
+			return err
+		}
+		if options.AutoAck {
+			if eventID, _ := ev["event_id"].(string); eventID != "" {
+				if err := c.AcknowledgeEvent(ctx, eventID, "", opts...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,209 @@
+package triggersapi
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how makeRequest retries a failed request.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the jittered exponential backoff.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryableStatusCodes overrides the default set (429, 502, 503, 504).
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy retries on 429/502/503/504 and network errors with
+// jittered exponential backoff: 200ms base, capped at 30s, 5 attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          5,
+		BaseDelay:            200 * time.Millisecond,
+		MaxDelay:             30 * time.Second,
+		RetryableStatusCodes: []int{429, 502, 503, 504},
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if len(p.RetryableStatusCodes) == 0 {
+		p.RetryableStatusCodes = d.RetryableStatusCodes
+	}
+	return p
+}
+
+func (p RetryPolicy) isRetryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the jittered delay before the given zero-indexed retry
+// attempt, honoring retryAfter (parsed from a Retry-After header) when set.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	d := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	return time.Duration(d/2 + mathrand.Float64()*d/2)
+}
+
+// requestConfig is the resolved state of a chain of RequestOptions.
+type requestConfig struct {
+	ctx            context.Context
+	idempotencyKey string
+	retry          RetryPolicy
+	headers        map[string]string
+	keyID          string
+}
+
+// RequestOption customizes a single call to a Client method.
+type RequestOption func(*requestConfig)
+
+// WithIdempotencyKey attaches an Idempotency-Key header to the request and
+// caches its signature so that retries, manual or automatic, reproduce the
+// same signature and timestamp within the client's idempotency window.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(cfg *requestConfig) { cfg.idempotencyKey = key }
+}
+
+// WithRetry overrides the retry policy for a single call.
+func WithRetry(policy RetryPolicy) RequestOption {
+	return func(cfg *requestConfig) { cfg.retry = policy.withDefaults() }
+}
+
+// WithContext attaches a context to a single call, used for cancellation.
+//
+// Deprecated: every Client method now takes a context.Context directly;
+// this option only has an effect on calls made through the NoContext shims.
+func WithContext(ctx context.Context) RequestOption {
+	return func(cfg *requestConfig) { cfg.ctx = ctx }
+}
+
+// WithHeader sets an additional header on the outgoing request.
+func WithHeader(key, value string) RequestOption {
+	return func(cfg *requestConfig) {
+		if cfg.headers == nil {
+			cfg.headers = make(map[string]string)
+		}
+		cfg.headers[key] = value
+	}
+}
+
+func resolveRequestConfig(opts []RequestOption) *requestConfig {
+	cfg := &requestConfig{retry: DefaultRetryPolicy()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.retry = cfg.retry.withDefaults()
+	return cfg
+}
+
+// resolveContext picks the context a request should run under: ctx as
+// passed by the caller, unless it's the zero-value context.Background()
+// and a RequestOption set one via the deprecated WithContext.
+func resolveContext(ctx context.Context, cfg *requestConfig) context.Context {
+	if ctx == context.Background() && cfg.ctx != nil {
+		return cfg.ctx
+	}
+	if ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// idempotencyEntry caches the signature produced for an idempotency key so
+// retries within the window reproduce it instead of signing a new timestamp.
+type idempotencyEntry struct {
+	timestamp string
+	signature string
+	bodyHash  string
+	expires   time.Time
+}
+
+// idempotencySignature returns the timestamp and signature to use for a
+// request carrying idempotencyKey, reusing the cached values when the key
+// was seen before with the same body within the client's idempotency window.
+func (c *Client) idempotencySignature(key, method, path, query, bodyHash string) (timestamp, signature string) {
+	c.idempotencyMu.Lock()
+	defer c.idempotencyMu.Unlock()
+
+	if entry, ok := c.idempotencyCache[key]; ok && entry.bodyHash == bodyHash && time.Now().Before(entry.expires) {
+		return entry.timestamp, entry.signature
+	}
+
+	timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+	if c.signingSecret != "" {
+		signature = c.generateSignature(method, path, query, timestamp, bodyHash)
+	}
+
+	window := c.idempotencyWindow
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	if c.idempotencyCache == nil {
+		c.idempotencyCache = make(map[string]idempotencyEntry)
+	}
+	c.purgeExpiredIdempotencyEntriesLocked()
+	c.idempotencyCache[key] = idempotencyEntry{timestamp: timestamp, signature: signature, bodyHash: bodyHash, expires: time.Now().Add(window)}
+	return timestamp, signature
+}
+
+// idempotencyPurgeInterval bounds how often purgeExpiredIdempotencyEntriesLocked
+// actually sweeps the cache, so a high-throughput caller minting many distinct
+// idempotency keys doesn't pay an O(n) scan on every single insert.
+const idempotencyPurgeInterval = time.Minute
+
+// purgeExpiredIdempotencyEntriesLocked removes every cache entry whose
+// window has already elapsed, at most once per idempotencyPurgeInterval.
+// idempotencySignature only ever checks expires on read, so without this
+// the cache would grow for the life of the Client on a long-running
+// AutoIdempotency producer or high-throughput CreateEventsFromChan caller,
+// even though old entries are never reused. Callers must hold idempotencyMu.
+func (c *Client) purgeExpiredIdempotencyEntriesLocked() {
+	now := time.Now()
+	if now.Sub(c.idempotencyLastPurge) < idempotencyPurgeInterval {
+		return
+	}
+	c.idempotencyLastPurge = now
+	for key, entry := range c.idempotencyCache {
+		if now.After(entry.expires) {
+			delete(c.idempotencyCache, key)
+		}
+	}
+}
+
+// newIdempotencyKey generates a random UUIDv4-formatted idempotency key for
+// AutoIdempotency.
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,443 @@
+package triggersapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport selects the connection mechanism used by Client.Subscribe.
+type Transport int
+
+const (
+	// TransportSSE streams events over a long-lived text/event-stream response.
+	TransportSSE Transport = iota
+	// TransportWebSocket streams events over a WebSocket connection.
+	TransportWebSocket
+)
+
+const (
+	wsReadDeadline = 60 * time.Second
+	wsPingInterval = 54 * time.Second
+	wsReadLimit    = 16 * 1024
+
+	// sseIdleTimeout bounds how long an SSE read may block without data,
+	// mirroring wsReadDeadline's role on the WebSocket transport.
+	sseIdleTimeout = 60 * time.Second
+)
+
+var errSubscriptionClosed = errors.New("triggersapi: subscription closed")
+
+// BackoffPolicy controls how Subscribe waits between reconnect attempts.
+// The zero value is replaced with DefaultBackoffPolicy.
+type BackoffPolicy struct {
+	Base   time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// DefaultBackoffPolicy returns the backoff used when SubscribeOptions.Backoff
+// is left at its zero value: 500ms base, doubling, capped at 30s.
+func DefaultBackoffPolicy() BackoffPolicy {
+	return BackoffPolicy{Base: 500 * time.Millisecond, Max: 30 * time.Second, Factor: 2}
+}
+
+func (b BackoffPolicy) next(attempt int) time.Duration {
+	if b.Base == 0 {
+		b = DefaultBackoffPolicy()
+	}
+	d := float64(b.Base) * math.Pow(b.Factor, float64(attempt))
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	return time.Duration(rand.Float64() * d)
+}
+
+// SubscribeOptions configures Client.Subscribe.
+type SubscribeOptions struct {
+	// Source and EventType filter the stream, mirroring GetInboxOptions.
+	Source    string
+	EventType string
+
+	// Transport selects SSE (the default) or WebSocket.
+	Transport Transport
+
+	// Backoff controls the delay between reconnect attempts.
+	Backoff BackoffPolicy
+}
+
+// Event is a single event delivered to a Subscription.
+type Event struct {
+	ID        string                 `json:"event_id"`
+	Source    string                 `json:"source"`
+	EventType string                 `json:"event_type"`
+	Payload   map[string]interface{} `json:"payload"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Subscription represents a live connection to the Triggers API event
+// stream opened by Client.Subscribe. Both Events and Errors must be drained
+// by the caller; the subscription reconnects automatically on transport
+// errors until Close is called.
+type Subscription struct {
+	events chan Event
+	errs   chan error
+
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Events returns the channel events are delivered on. It is closed once the
+// subscription has been closed and its connection torn down.
+func (s *Subscription) Events() <-chan Event { return s.events }
+
+// Errors returns the channel connection errors are reported on. A reconnect
+// is attempted automatically after each error.
+func (s *Subscription) Errors() <-chan error { return s.errs }
+
+// Close stops the subscription and releases its connection. It cancels the
+// context the subscription's in-flight request was made with, so a blocked
+// read on an idle stream is interrupted immediately rather than waiting for
+// the next line or a read deadline. It is idempotent and safe to call more
+// than once.
+func (s *Subscription) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.cancel()
+	})
+	return nil
+}
+
+// wait blocks for the next backoff interval, returning false if the
+// subscription was closed while waiting.
+func (s *Subscription) wait(backoff BackoffPolicy, attempt *int) bool {
+	d := backoff.next(*attempt)
+	*attempt++
+	select {
+	case <-time.After(d):
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Subscription) reportErr(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+// Subscribe opens a long-lived connection to the Triggers API and delivers
+// events as they arrive, reconnecting automatically on transport errors.
+// It replaces polling GetInbox for callers that need low-latency delivery.
+// ctx bounds the subscription's entire lifetime: canceling it has the same
+// effect as calling Subscription.Close.
+func (c *Client) Subscribe(ctx context.Context, options SubscribeOptions) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		events: make(chan Event, 16),
+		errs:   make(chan error, 1),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	switch options.Transport {
+	case TransportWebSocket:
+		go c.runWebSocketSubscription(ctx, sub, options)
+	default:
+		go c.runSSESubscription(ctx, sub, options)
+	}
+
+	return sub, nil
+}
+
+// SubscribeNoContext is the pre-context signature of Subscribe, retained
+// during the migration to context-aware calls.
+//
+// Deprecated: use Subscribe, which takes a context.Context, instead.
+func (c *Client) SubscribeNoContext(options SubscribeOptions) (*Subscription, error) {
+	return c.Subscribe(context.Background(), options)
+}
+
+func (c *Client) subscribeURL(options SubscribeOptions) (*url.URL, error) {
+	reqURL, err := url.Parse(c.baseURL + "/v1/events/stream")
+	if err != nil {
+		return nil, err
+	}
+	q := reqURL.Query()
+	if options.Source != "" {
+		q.Set("source", options.Source)
+	}
+	if options.EventType != "" {
+		q.Set("event_type", options.EventType)
+	}
+	reqURL.RawQuery = q.Encode()
+	return reqURL, nil
+}
+
+func (c *Client) runSSESubscription(ctx context.Context, sub *Subscription, options SubscribeOptions) {
+	defer close(sub.events)
+
+	lastEventID := ""
+	attempt := 0
+
+	for {
+		select {
+		case <-sub.done:
+			return
+		default:
+		}
+
+		reqURL, err := c.subscribeURL(options)
+		if err != nil {
+			sub.reportErr(err)
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+		if err != nil {
+			sub.reportErr(err)
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("X-API-Key", c.apiKey)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		c.signRequest(req, sha256Hash(nil))
+
+		var conn net.Conn
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { conn = info.Conn },
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+		resp, err := c.streamClient.Do(req)
+		if err != nil {
+			sub.reportErr(err)
+			if !sub.wait(options.Backoff, &attempt) {
+				return
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			resp.Body.Close()
+			sub.reportErr(fmt.Errorf("subscribe: unexpected status %d", resp.StatusCode))
+			if !sub.wait(options.Backoff, &attempt) {
+				return
+			}
+			continue
+		}
+
+		attempt = 0
+		if conn != nil {
+			conn.SetReadDeadline(time.Now().Add(sseIdleTimeout))
+		}
+		lastEventID, err = c.consumeSSE(resp, sub, lastEventID, conn)
+		resp.Body.Close()
+		if err != nil {
+			sub.reportErr(err)
+		}
+
+		select {
+		case <-sub.done:
+			return
+		default:
+		}
+		if !sub.wait(options.Backoff, &attempt) {
+			return
+		}
+	}
+}
+
+// consumeSSE reads events from resp.Body until the stream ends, sub is
+// closed, or an error occurs. When conn is non-nil (the underlying
+// connection, captured via httptrace), its read deadline is refreshed after
+// every line so an idle stream is detected the same way the WebSocket
+// transport detects one, and canceling the request's context (via
+// Subscription.Close) unblocks a read that's already in flight.
+func (c *Client) consumeSSE(resp *http.Response, sub *Subscription, lastEventID string, conn net.Conn) (string, error) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var id, eventType, data string
+	flush := func() error {
+		if data == "" {
+			return nil
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return err
+		}
+		if eventType != "" && ev.EventType == "" {
+			ev.EventType = eventType
+		}
+		if id != "" {
+			ev.ID = id
+			lastEventID = id
+		}
+		select {
+		case sub.events <- ev:
+		case <-sub.done:
+			return errSubscriptionClosed
+		}
+		id, eventType, data = "", "", ""
+		return nil
+	}
+
+	for scanner.Scan() {
+		if conn != nil {
+			conn.SetReadDeadline(time.Now().Add(sseIdleTimeout))
+		}
+		select {
+		case <-sub.done:
+			return lastEventID, nil
+		default:
+		}
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				if err == errSubscriptionClosed {
+					return lastEventID, nil
+				}
+				return lastEventID, err
+			}
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	return lastEventID, scanner.Err()
+}
+
+func (c *Client) runWebSocketSubscription(ctx context.Context, sub *Subscription, options SubscribeOptions) {
+	defer close(sub.events)
+
+	attempt := 0
+
+	for {
+		select {
+		case <-sub.done:
+			return
+		default:
+		}
+
+		reqURL, err := c.subscribeURL(options)
+		if err != nil {
+			sub.reportErr(err)
+			return
+		}
+		if reqURL.Scheme == "https" {
+			reqURL.Scheme = "wss"
+		} else {
+			reqURL.Scheme = "ws"
+		}
+
+		header := http.Header{}
+		header.Set("X-API-Key", c.apiKey)
+		handshake, _ := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+		c.signRequest(handshake, sha256Hash(nil))
+		for k := range handshake.Header {
+			header.Set(k, handshake.Header.Get(k))
+		}
+
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, reqURL.String(), header)
+		if err != nil {
+			sub.reportErr(err)
+			if !sub.wait(options.Backoff, &attempt) {
+				return
+			}
+			continue
+		}
+
+		attempt = 0
+		if err := c.consumeWebSocket(conn, sub); err != nil {
+			sub.reportErr(err)
+		}
+		conn.Close()
+
+		select {
+		case <-sub.done:
+			return
+		default:
+		}
+		if !sub.wait(options.Backoff, &attempt) {
+			return
+		}
+	}
+}
+
+func (c *Client) consumeWebSocket(conn *websocket.Conn, sub *Subscription) error {
+	conn.SetReadLimit(wsReadLimit)
+	conn.SetReadDeadline(time.Now().Add(wsReadDeadline))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(wsReadDeadline))
+		return nil
+	})
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-pingDone:
+				return
+			case <-sub.done:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-sub.done:
+			return nil
+		default:
+		}
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var ev Event
+		if err := json.Unmarshal(message, &ev); err != nil {
+			sub.reportErr(err)
+			continue
+		}
+
+		select {
+		case sub.events <- ev:
+		case <-sub.done:
+			return nil
+		}
+	}
+}